@@ -0,0 +1,172 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"io/ioutil"
+	"log"
+	"os"
+	"regexp"
+	"strings"
+	"text/template"
+
+	"golang.org/x/tools/go/ast/astutil"
+	"golang.org/x/tools/go/packages"
+)
+
+// missingMethodRe matches the two shapes the gc type checker uses to report
+// a concrete type failing to implement an interface, e.g.
+//
+//	cannot use x (variable of type T) as I value in assignment: T does not implement I (missing method M)
+//	cannot convert x (variable of type T) to type I: T does not implement I (missing method M)
+var missingMethodRe = regexp.MustCompile(`: (\S+) does not implement (\S+) \(missing method (\w+)\)`)
+
+// bareIdent strips a leading pointer indicator and package qualifier from a
+// types.Error type string, leaving the bare identifier sumgen can look up in
+// the package scope.
+func bareIdent(s string) string {
+	s = strings.TrimPrefix(s, "*")
+	if i := strings.LastIndexByte(s, '.'); i >= 0 {
+		s = s[i+1:]
+	}
+	return s
+}
+
+// fromErrors loads the package in the current directory, type-checks it, and
+// scans the resulting TypeErrors for "does not implement" diagnostics. For
+// each one it infers the concrete type, the interface, and the missing
+// method set the same way an explicit "Iface = TypeA | TypeB" definition
+// would, then writes the stubs into the file declaring the concrete type
+// instead of DIR_sumgen.go. Grouping by destination file, rather than by
+// definition, is what lets two mismatches against the same concrete type
+// merge into a single appended block instead of two.
+func fromErrors(tmpl *template.Template) error {
+	cfg := &packages.Config{
+		Mode: packages.NeedName |
+			packages.NeedFiles |
+			packages.NeedImports |
+			packages.NeedTypes |
+			packages.NeedTypesInfo |
+			packages.NeedSyntax |
+			packages.NeedDeps,
+	}
+	pkgs, err := packages.Load(cfg)
+	if err != nil {
+		return err
+	}
+	if len(pkgs) != 1 {
+		return fmt.Errorf("could not find Go package in current directory")
+	}
+	pkg := pkgs[0]
+
+	type target struct {
+		methods []method
+		imports map[string]struct{}
+		order   []string
+	}
+	byFile := make(map[string]target)
+	seen := make(map[string]bool)
+
+	for _, te := range pkg.TypeErrors {
+		m := missingMethodRe.FindStringSubmatch(te.Msg)
+		if m == nil {
+			continue
+		}
+		concreteName, ifaceName := bareIdent(m[1]), bareIdent(m[2])
+		key := concreteName + "|" + ifaceName
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+
+		t := pkg.Types.Scope().Lookup(ifaceName)
+		if t == nil {
+			continue
+		}
+		iface, ok := t.Type().Underlying().(*types.Interface)
+		if !ok {
+			continue
+		}
+		obj := pkg.Types.Scope().Lookup(concreteName)
+		if obj == nil {
+			continue
+		}
+
+		order, err := methodOrder(pkg, t)
+		if err != nil {
+			return err
+		}
+		methods, err := appendMissing(pkg, nil, iface, rhs{Ptr: strings.HasPrefix(m[1], "*"), Type: concreteName})
+		if err != nil {
+			log.Printf("sumgen: skipping %s: %v", concreteName, err)
+			continue
+		}
+		methods, imports, err := clean(pkg, methods, order)
+		if err != nil {
+			return err
+		}
+		if len(methods) == 0 {
+			continue
+		}
+
+		fname := pkg.Fset.Position(obj.Pos()).Filename
+		tgt := byFile[fname]
+		tgt.methods = append(tgt.methods, methods...)
+		tgt.order = append(tgt.order, order...)
+		if tgt.imports == nil {
+			tgt.imports = make(map[string]struct{})
+		}
+		for im := range imports {
+			tgt.imports[im] = struct{}{}
+		}
+		byFile[fname] = tgt
+	}
+
+	for fname, tgt := range byFile {
+		methods, _, err := clean(pkg, tgt.methods, tgt.order)
+		if err != nil {
+			return err
+		}
+		if err := appendStubs(fname, methods, tgt.imports, tmpl); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// appendStubs appends the given stub methods to the end of fname and
+// reformats the result in place, the same way sumgen appends to
+// DIR_sumgen.go, except the target is an existing source file.
+func appendStubs(fname string, methods []method, imports map[string]struct{}, tmpl *template.Template) error {
+	b, err := ioutil.ReadFile(fname)
+	if err != nil {
+		return err
+	}
+	buf := bytes.NewBuffer(b)
+	for _, m := range methods {
+		stub, err := m.Render(tmpl)
+		if err != nil {
+			return err
+		}
+		buf.WriteString("\n")
+		buf.WriteString(stub)
+	}
+	fset := token.NewFileSet()
+	root, err := parser.ParseFile(fset, fname, buf, parser.ParseComments)
+	if err != nil {
+		return err
+	}
+	for im := range imports {
+		astutil.AddImport(fset, root, im)
+	}
+	file, err := os.Create(fname)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	return format.Node(file, fset, root)
+}
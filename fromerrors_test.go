@@ -0,0 +1,154 @@
+package main
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// chdirTemp writes src as the sole file of a throwaway module in a temp
+// directory, chdirs into it for the duration of the test, and returns the
+// directory so callers can inspect files fromErrors/generate wrote there.
+func chdirTemp(t *testing.T, src string) string {
+	t.Helper()
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "go.mod"), []byte("module sumgentest\n\ngo 1.21\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "pkg.go"), []byte(src), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	cwd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Chdir(cwd) })
+	return dir
+}
+
+// typeCheckErrors parses and type-checks src as a standalone file and returns
+// the error strings the type checker reported, so tests can match
+// missingMethodRe against real types.Error text rather than guessed strings.
+func typeCheckErrors(t *testing.T, src string) []string {
+	t.Helper()
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, "test.go", src, 0)
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	var errs []string
+	conf := types.Config{Error: func(err error) { errs = append(errs, err.Error()) }}
+	conf.Check("test", fset, []*ast.File{file}, nil)
+	if len(errs) == 0 {
+		t.Fatalf("type-checking produced no errors, expected at least one")
+	}
+	return errs
+}
+
+func TestMissingMethodRe(t *testing.T) {
+	tests := []struct {
+		name                                string
+		src                                 string
+		wantConcrete, wantIface, wantMethod string
+	}{
+		{
+			name: "cannot use",
+			src: `package test
+
+type I interface{ M() }
+type T struct{}
+
+var _ I = T{}
+`,
+			wantConcrete: "T", wantIface: "I", wantMethod: "M",
+		},
+		{
+			name: "cannot convert",
+			src: `package test
+
+type I interface{ M() }
+type T struct{}
+
+func f(t T) I { return I(t) }
+`,
+			wantConcrete: "T", wantIface: "I", wantMethod: "M",
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			var matched bool
+			for _, e := range typeCheckErrors(t, tc.src) {
+				m := missingMethodRe.FindStringSubmatch(e)
+				if m == nil {
+					continue
+				}
+				matched = true
+				if got := bareIdent(m[1]); got != tc.wantConcrete {
+					t.Errorf("concrete = %q, want %q (from %q)", got, tc.wantConcrete, e)
+				}
+				if got := bareIdent(m[2]); got != tc.wantIface {
+					t.Errorf("iface = %q, want %q (from %q)", got, tc.wantIface, e)
+				}
+				if m[3] != tc.wantMethod {
+					t.Errorf("method = %q, want %q (from %q)", m[3], tc.wantMethod, e)
+				}
+			}
+			if !matched {
+				t.Fatalf("missingMethodRe matched none of: %v", typeCheckErrors(t, tc.src))
+			}
+		})
+	}
+}
+
+func TestBareIdent(t *testing.T) {
+	cases := []struct{ in, want string }{
+		{"T", "T"},
+		{"*T", "T"},
+		{"pkg.T", "T"},
+		{"*pkg.T", "T"},
+	}
+	for _, c := range cases {
+		if got := bareIdent(c.in); got != c.want {
+			t.Errorf("bareIdent(%q) = %q, want %q", c.in, got, c.want)
+		}
+	}
+}
+
+// TestFromErrorsDedupesByFile exercises fromErrors' grouping logic: the same
+// concrete/interface mismatch reported twice (once per call site) must only
+// append one stub, merged into the file declaring the concrete type.
+func TestFromErrorsDedupesByFile(t *testing.T) {
+	chdirTemp(t, `package p
+
+type I interface{ M() }
+type T struct{}
+
+func a() I { return T{} }
+func b() I { return T{} }
+`)
+	tmpl, err := parseBodyTemplate("panic")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := fromErrors(tmpl); err != nil {
+		t.Fatalf("fromErrors: %v", err)
+	}
+	b, err := os.ReadFile("pkg.go")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := strings.Count(string(b), "func (_ T) M()"); n != 1 {
+		t.Fatalf("got %d stubs for T.M, want exactly 1 (dedup failed):\n%s", n, b)
+	}
+	if _, err := parser.ParseFile(token.NewFileSet(), "pkg.go", b, 0); err != nil {
+		t.Fatalf("generated file does not parse: %v", err)
+	}
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/template"
+
+	"golang.org/x/tools/go/packages"
+)
+
+const directivePrefix = "//sumgen:"
+
+// scanDirectives walks the package in the current directory for
+// "//sumgen: Iface = A | *B" directive comments, parses each as a def, and
+// merges the results into a single DIR_sumgen.go the same way sumgen does
+// for explicit command-line definitions. This lets a sum-type definition
+// live next to the interface it describes, regenerated with `go generate`.
+func scanDirectives(tmpl *template.Template, sealed bool) error {
+	cwd, err := os.Getwd()
+	if err != nil {
+		return err
+	}
+	cfg := &packages.Config{
+		Mode: packages.NeedName |
+			packages.NeedFiles |
+			packages.NeedImports |
+			packages.NeedTypes |
+			packages.NeedSyntax,
+	}
+	pkgs, err := packages.Load(cfg)
+	if err != nil {
+		return err
+	}
+	if len(pkgs) != 1 {
+		return fmt.Errorf("could not find Go package in current directory")
+	}
+	pkg := pkgs[0]
+
+	var defs []def
+	for _, file := range pkg.Syntax {
+		for _, cg := range file.Comments {
+			for _, c := range cg.List {
+				if !strings.HasPrefix(c.Text, directivePrefix) {
+					continue
+				}
+				spec := strings.TrimSpace(strings.TrimPrefix(c.Text, directivePrefix))
+				sum, err := parseDef(spec)
+				if err != nil {
+					return fmt.Errorf("invalid %s directive %q: %v", directivePrefix, c.Text, err)
+				}
+				defs = append(defs, sum)
+			}
+		}
+	}
+	if len(defs) == 0 {
+		return fmt.Errorf("no %s directives found in package", directivePrefix)
+	}
+
+	fname := filepath.Base(cwd) + "_sumgen.go"
+	return generate(pkg, defs, tmpl, fname, sealed)
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"go/types"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestScanDirectives(t *testing.T) {
+	dir := chdirTemp(t, `package p
+
+//sumgen: Expr = IntLit | *BinOp
+type Expr interface {
+	isExpr()
+}
+
+type IntLit struct{}
+type BinOp struct{}
+`)
+	tmpl, err := parseBodyTemplate("panic")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := scanDirectives(tmpl, false); err != nil {
+		t.Fatalf("scanDirectives: %v", err)
+	}
+	out, err := os.ReadFile(filepath.Join(dir, filepath.Base(dir)+"_sumgen.go"))
+	if err != nil {
+		t.Fatalf("reading generated file: %v", err)
+	}
+	for _, want := range []string{
+		"func (_ IntLit) isExpr()",
+		"func (_ *BinOp) isExpr()",
+		"func SwitchExpr(",
+	} {
+		if !strings.Contains(string(out), want) {
+			t.Errorf("generated file missing %q:\n%s", want, out)
+		}
+	}
+}
+
+func TestScanDirectivesNoneFound(t *testing.T) {
+	chdirTemp(t, `package p
+
+type Expr interface {
+	isExpr()
+}
+`)
+	tmpl, err := parseBodyTemplate("panic")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := scanDirectives(tmpl, false); err == nil {
+		t.Fatal("scanDirectives returned nil, want an error when no directives are present")
+	}
+}
+
+// TestCleanDedupesAcrossInterfaces covers clean merging methods for a type
+// that implements two distinct sum-type interfaces, so the shared method
+// isn't emitted twice (the behavior -scan's multi-def merge relies on).
+func TestCleanDedupesAcrossInterfaces(t *testing.T) {
+	pkg := loadTestPackage(t, `package pkg
+
+type A interface{ M() }
+type B interface{ M() }
+
+type T struct{}
+`)
+	ifaceA := pkg.Types.Scope().Lookup("A").Type().Underlying().(*types.Interface)
+	ifaceB := pkg.Types.Scope().Lookup("B").Type().Underlying().(*types.Interface)
+
+	var methods []method
+	var err error
+	methods, err = appendMissing(pkg, methods, ifaceA, rhs{Type: "T"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	methods, err = appendMissing(pkg, methods, ifaceB, rhs{Type: "T"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(methods) != 2 {
+		t.Fatalf("appendMissing produced %d methods before clean, want 2", len(methods))
+	}
+	methods, _, err = clean(pkg, methods, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(methods) != 1 {
+		t.Fatalf("clean produced %d methods, want 1 (dedup across A and B)", len(methods))
+	}
+}
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"os"
+	"strings"
+)
+
+// genMarker generates the private marker method that seals r into lhs.
+// Its body is always empty: it exists only so the method set of r can't be
+// satisfied by a type outside this package, turning Lhs into a closed,
+// tagged union.
+func genMarker(lhs string, r rhs) string {
+	ptr := ""
+	if r.Ptr {
+		ptr = "*"
+	}
+	return fmt.Sprintf("func (_ %s%s) isSum%s() {}\n", ptr, r.Type, lhs)
+}
+
+// injectMarker rewrites the file declaring lhsObj in place, adding an
+// isSum<Lhs>() method to its interface declaration if it isn't already
+// there. This is the standard Go idiom for sealing an interface: callers
+// outside the package can no longer implement it, since they can't spell
+// the unexported method name.
+func injectMarker(lhsObj types.Object, markerName string, fset *token.FileSet) error {
+	pos := fset.Position(lhsObj.Pos())
+	if pos.Filename == "" {
+		return fmt.Errorf("could not resolve source file declaring %q", lhsObj.Name())
+	}
+	editFset := token.NewFileSet()
+	file, err := parser.ParseFile(editFset, pos.Filename, nil, parser.ParseComments)
+	if err != nil {
+		return err
+	}
+	var sealed bool
+	ast.Inspect(file, func(n ast.Node) bool {
+		ts, ok := n.(*ast.TypeSpec)
+		if !ok || ts.Name.Name != lhsObj.Name() {
+			return !sealed
+		}
+		it, ok := ts.Type.(*ast.InterfaceType)
+		if !ok {
+			return false
+		}
+		for _, m := range it.Methods.List {
+			if len(m.Names) == 1 && m.Names[0].Name == markerName {
+				sealed = true
+				return false
+			}
+		}
+		it.Methods.List = append(it.Methods.List, &ast.Field{
+			Names: []*ast.Ident{ast.NewIdent(markerName)},
+			Type:  &ast.FuncType{Params: &ast.FieldList{}},
+		})
+		sealed = true
+		return false
+	})
+	if !sealed {
+		return fmt.Errorf("could not find interface type %q to seal", lhsObj.Name())
+	}
+	f, err := os.Create(pos.Filename)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return format.Node(f, editFset, file)
+}
+
+// lowerFirst returns s with its first rune lower-cased, for deriving an
+// unexported identifier from an exported type name.
+func lowerFirst(s string) string {
+	if s == "" {
+		return s
+	}
+	return strings.ToLower(s[:1]) + s[1:]
+}
+
+// genCodec generates a JSON envelope codec for the sum type described by
+// sum: Marshal<Lhs>/Unmarshal<Lhs> functions round-tripping through
+// {"type":"TypeName","value":...}. Interface types can't carry methods of
+// their own in Go, so these are free functions rather than a MarshalJSON on
+// Lhs itself; json.Marshal/Unmarshal on the underlying value already honor
+// any `json:` tags the RHS structs declare.
+func genCodec(sum def) string {
+	env := lowerFirst(sum.Lhs) + "Envelope"
+	var b strings.Builder
+	fmt.Fprintf(&b, "type %s struct {\n\tType  string          `json:\"type\"`\n\tValue json.RawMessage `json:\"value\"`\n}\n\n", env)
+
+	fmt.Fprintf(&b, "func Marshal%s(v %s) ([]byte, error) {\n\tvar typ string\n\tswitch v.(type) {\n", sum.Lhs, sum.Lhs)
+	for _, r := range sum.Rhs {
+		ptr := ""
+		if r.Ptr {
+			ptr = "*"
+		}
+		fmt.Fprintf(&b, "\tcase %s%s:\n\t\ttyp = %q\n", ptr, r.Type, r.Type)
+	}
+	fmt.Fprintf(&b, "\tdefault:\n\t\treturn nil, fmt.Errorf(\"unhandled %%T for %s\", v)\n\t}\n", sum.Lhs)
+	b.WriteString("\tval, err := json.Marshal(v)\n\tif err != nil {\n\t\treturn nil, err\n\t}\n")
+	fmt.Fprintf(&b, "\treturn json.Marshal(%s{Type: typ, Value: val})\n}\n\n", env)
+
+	fmt.Fprintf(&b, "func Unmarshal%s(data []byte) (%s, error) {\n\tvar env %s\n\tif err := json.Unmarshal(data, &env); err != nil {\n\t\treturn nil, err\n\t}\n\tswitch env.Type {\n", sum.Lhs, sum.Lhs, env)
+	for _, r := range sum.Rhs {
+		ret := "v"
+		if r.Ptr {
+			ret = "&v"
+		}
+		fmt.Fprintf(&b, "\tcase %q:\n\t\tvar v %s\n\t\tif err := json.Unmarshal(env.Value, &v); err != nil {\n\t\t\treturn nil, err\n\t\t}\n\t\treturn %s, nil\n", r.Type, r.Type, ret)
+	}
+	fmt.Fprintf(&b, "\tdefault:\n\t\treturn nil, fmt.Errorf(\"unknown type %%q for %s\", env.Type)\n\t}\n}\n", sum.Lhs)
+	return b.String()
+}
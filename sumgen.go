@@ -15,6 +15,7 @@ import (
 	"sort"
 	"strings"
 	"text/scanner"
+	"text/template"
 	"unicode"
 
 	"golang.org/x/tools/go/ast/astutil"
@@ -22,7 +23,10 @@ import (
 )
 
 func usage() {
-	fmt.Fprintf(os.Stderr, "usage: sumgen \"InterfaceName = TypeNameA, *TypeNameB, ...\"\n")
+	fmt.Fprintf(os.Stderr, "usage: sumgen \"InterfaceName = TypeNameA | *TypeNameB | ...\" [\"OtherIface = ...\" ...]\n")
+	fmt.Fprintf(os.Stderr, "       sumgen -fromerrors\n")
+	fmt.Fprintf(os.Stderr, "       sumgen -scan\n")
+	fmt.Fprintf(os.Stderr, "       sumgen -check \"InterfaceName = TypeNameA | *TypeNameB | ...\"\n")
 	os.Exit(2)
 }
 
@@ -53,19 +57,34 @@ type def struct {
 }
 
 type method struct {
-	Ptr    bool
-	Recv   string
-	Name   string
-	Sig    *types.Signature
-	SigStr string
+	Ptr         bool
+	Recv        string
+	Name        string
+	Sig         *types.Signature
+	SigStr      string
+	Results     []string
+	ZeroResults string
 }
 
-func (m method) String() string {
+// Render executes tmpl to produce the method body and returns the full stub
+// declaration. tmpl sees the fields documented on stubData.
+func (m method) Render(tmpl *template.Template) (string, error) {
 	var ptr string
 	if m.Ptr {
 		ptr = "*"
 	}
-	return fmt.Sprintf("func (_ %s%s) %s%s { panic(\"default implementation\") }\n", ptr, m.Recv, m.Name, m.SigStr)
+	var body bytes.Buffer
+	data := stubData{
+		Recv:        m.Recv,
+		Name:        m.Name,
+		Sig:         m.SigStr,
+		Results:     m.Results,
+		ZeroResults: m.ZeroResults,
+	}
+	if err := tmpl.Execute(&body, data); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("func (_ %s%s) %s%s { %s }\n", ptr, m.Recv, m.Name, m.SigStr, body.String()), nil
 }
 
 // Def = LhsType "=" RhsType { "|" RhsType } .
@@ -148,8 +167,16 @@ func appendMissing(pkg *packages.Package, methods []method, iface *types.Interfa
 }
 
 // Remove duplicate methods for a given type, and update imports as we go.
-func clean(pkg *packages.Package, methods []method) ([]method, map[string]struct{}, error) {
+// order gives the textual order method names appear in the interface
+// declaration (see methodOrder); methods not found in it (or a nil order,
+// e.g. when callers merge methods from several interfaces) sort after the
+// ones that are, in lexical order.
+func clean(pkg *packages.Package, methods []method, order []string) ([]method, map[string]struct{}, error) {
 	imports := make(map[string]struct{})
+	rank := make(map[string]int, len(order))
+	for i, name := range order {
+		rank[name] = i
+	}
 	cmp := func(ma, mb method) int {
 		if ma.Recv == mb.Recv && ma.Name == mb.Name {
 			if types.Identical(ma.Sig, mb.Sig) {
@@ -163,7 +190,7 @@ func clean(pkg *packages.Package, methods []method) ([]method, map[string]struct
 	sort.Slice(methods, func(i, j int) bool {
 		// Precedence
 		// 1. Receiver
-		// 2. Name
+		// 2. Position in the interface declaration, falling back to name
 		// 3. Signature implies separate equivalence class.
 		// 4. a < *a
 		mi, mj := methods[i], methods[j]
@@ -171,7 +198,16 @@ func clean(pkg *packages.Package, methods []method) ([]method, map[string]struct
 			return mi.Recv < mj.Recv
 		}
 		if mi.Name != mj.Name {
-			return mi.Name < mj.Name
+			ri, oki := rank[mi.Name]
+			rj, okj := rank[mj.Name]
+			switch {
+			case oki && okj:
+				return ri < rj
+			case oki != okj:
+				return oki
+			default:
+				return mi.Name < mj.Name
+			}
 		}
 		if !types.Identical(mi.Sig, mj.Sig) {
 			return true
@@ -189,13 +225,15 @@ func clean(pkg *packages.Package, methods []method) ([]method, map[string]struct
 			return nil, nil, fmt.Errorf("method %q defined multiple times", methods[i].Name)
 		case curr == i:
 			// update imports
-			methods[curr].SigStr = types.TypeString(methods[curr].Sig, func(other *types.Package) string {
+			qualifier := func(other *types.Package) string {
 				if pkg.Types == other {
 					return ""
 				}
 				imports[other.Path()] = struct{}{}
 				return other.Name()
-			})[4:]
+			}
+			methods[curr].SigStr = types.TypeString(methods[curr].Sig, qualifier)[4:]
+			methods[curr].Results, methods[curr].ZeroResults = zeroResults(methods[curr].Sig, qualifier)
 		case c == 0:
 			methods = append(methods[:i], methods[i+1:]...)
 			i--
@@ -207,8 +245,12 @@ func clean(pkg *packages.Package, methods []method) ([]method, map[string]struct
 	return methods, imports, nil
 }
 
-func sumgen(def string) error {
-	// Parse specified package
+// sumgen loads the package in the current directory and generates stubs and
+// a Switch helper for each "Iface = A | *B" definition, merging them into a
+// single DIR_sumgen.go. Passing multiple defs in one invocation lets clean
+// deduplicate across interfaces, so a type implementing more than one sum
+// doesn't get a method emitted twice.
+func sumgen(defStrs []string, tmpl *template.Template, sealed bool) error {
 	cwd, err := os.Getwd()
 	if err != nil {
 		return err
@@ -228,35 +270,87 @@ func sumgen(def string) error {
 	}
 	pkg := pkgs[0]
 
-	// Parse sum-type definition
-	sum, err := parseDef(def)
-	if err != nil {
-		return err
+	defs := make([]def, len(defStrs))
+	for i, s := range defStrs {
+		sum, err := parseDef(s)
+		if err != nil {
+			return err
+		}
+		defs[i] = sum
 	}
 
-	// Find interface type declaration
-	var iface *types.Interface
-	if t := pkg.Types.Scope().Lookup(sum.Lhs); t != nil {
-		iface, _ = t.Type().Underlying().(*types.Interface)
-	}
-	if iface == nil {
-		return fmt.Errorf("no interface type with name %q", sum.Lhs)
-	}
+	fname := filepath.Base(cwd) + "_sumgen.go"
+	return generate(pkg, defs, tmpl, fname, sealed)
+}
 
-	// Look up missing methods for each RHS type.
+// generate resolves every def against pkg, writes the missing stub methods
+// and a Switch helper for each into fname, and reformats the result.
+// fname is read first if it already exists, so repeated invocations (e.g.
+// re-running -scan under go generate) accumulate into one file. When
+// sealed is set, each RHS also gets a private isSum<Lhs> marker method (with
+// the interface itself rewritten in place to require it) plus a JSON
+// envelope codec for the sum.
+func generate(pkg *packages.Package, defs []def, tmpl *template.Template, fname string, sealed bool) error {
 	var methods []method
-	for _, rhs := range sum.Rhs {
-		if methods, err = appendMissing(pkg, methods, iface, rhs); err != nil {
+	var order []string
+	var extra []string
+	for _, sum := range defs {
+		// Find interface type declaration
+		var lhsObj types.Object
+		var iface *types.Interface
+		if t := pkg.Types.Scope().Lookup(sum.Lhs); t != nil {
+			lhsObj = t
+			iface, _ = t.Type().Underlying().(*types.Interface)
+		}
+		if iface == nil {
+			return fmt.Errorf("no interface type with name %q", sum.Lhs)
+		}
+		ord, err := methodOrder(pkg, lhsObj)
+		if err != nil {
 			return err
 		}
+		order = append(order, ord...)
+
+		// Look up missing methods for each RHS type.
+		for _, rhs := range sum.Rhs {
+			if methods, err = appendMissing(pkg, methods, iface, rhs); err != nil {
+				return err
+			}
+		}
+
+		if sealed {
+			markerName := "isSum" + sum.Lhs
+			if err := injectMarker(lhsObj, markerName, pkg.Fset); err != nil {
+				return err
+			}
+			for _, r := range sum.Rhs {
+				// Skip a variant that already has the marker method, the
+				// same way appendMissing skips a method the concrete type
+				// already implements: otherwise a repeat invocation (e.g.
+				// under -scan/go generate) redeclares it.
+				if o := pkg.Types.Scope().Lookup(r.Type); o != nil {
+					if m, _, _ := types.LookupFieldOrMethod(o.Type(), r.Ptr, pkg.Types, markerName); m != nil {
+						continue
+					}
+				}
+				extra = append(extra, genMarker(sum.Lhs, r))
+			}
+			// Skip the codec (envelope type + Marshal/Unmarshal) if a
+			// previous run already generated it.
+			if pkg.Types.Scope().Lookup("Marshal"+sum.Lhs) == nil {
+				extra = append(extra, genCodec(sum))
+			}
+		}
 	}
-	methods, imports, err := clean(pkg, methods)
+	methods, imports, err := clean(pkg, methods, order)
 	if err != nil {
 		return err
 	}
+	if sealed {
+		imports["encoding/json"] = struct{}{}
+	}
 
 	// Output source file
-	fname := filepath.Base(cwd) + "_sumgen.go"
 	var buf *bytes.Buffer
 	if _, stat := os.Stat(fname); stat == nil {
 		// If DIRNAME_sumgen.go exists, read it into memory
@@ -272,8 +366,29 @@ func sumgen(def string) error {
 	}
 	// append methods
 	for _, m := range methods {
-		buf.WriteString(m.String())
+		stub, err := m.Render(tmpl)
+		if err != nil {
+			return err
+		}
+		buf.WriteString(stub)
 	}
+	// append the exhaustiveness-checking switch helper for each def, unless
+	// a previous run (or handwritten code) already declared it: generate is
+	// expected to be re-run on an existing DIR_sumgen.go, e.g. under -scan
+	// and go generate, so re-emitting it would redeclare the function.
+	for _, sum := range defs {
+		if pkg.Types.Scope().Lookup("Switch"+sum.Lhs) != nil {
+			continue
+		}
+		buf.WriteString("\n")
+		buf.WriteString(genSwitch(sum))
+	}
+	// append sealed markers and JSON codecs, if requested
+	for _, src := range extra {
+		buf.WriteString("\n")
+		buf.WriteString(src)
+	}
+	imports["fmt"] = struct{}{}
 	// parse constucted file
 	fset := token.NewFileSet()
 	root, err := parser.ParseFile(fset, "", buf, parser.ParseComments)
@@ -299,13 +414,55 @@ func sumgen(def string) error {
 func main() {
 	log.SetPrefix("sumgen: ")
 	log.SetFlags(0)
+	fromerrors := flag.Bool("fromerrors", false, "infer sum-type definitions from \"does not implement\" type errors instead of a definition argument")
+	body := flag.String("body", "panic", "stub body: one of the builtins panic, zero, todo, or a text/template over stubData")
+	check := flag.Bool("check", false, "report existing type switches over the definition's interface that are missing a case, instead of generating stubs")
+	scan := flag.Bool("scan", false, "scan the package for \"//sumgen: Iface = A | *B\" directive comments instead of taking definitions as arguments")
+	sealed := flag.Bool("sealed", false, "seal each RHS with a private marker method disallowing external implementers, and generate a JSON envelope codec")
 	flag.Usage = usage
 	flag.Parse()
-	def := strings.Join(flag.Args(), "")
-	if len(def) == 0 {
+
+	tmpl, err := parseBodyTemplate(*body)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	if *check && (*fromerrors || *scan) {
+		log.Fatal("-check cannot be combined with -fromerrors or -scan: it reports on an explicit definition argument, which those modes don't take")
+	}
+	if *sealed && *fromerrors {
+		log.Fatal("-sealed is not supported with -fromerrors: -fromerrors appends stubs to the files already declaring each concrete type, and has no single file to rewrite the interface declaration or host the JSON codec in")
+	}
+
+	if *fromerrors {
+		if err := fromErrors(tmpl); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
+	if *scan {
+		if err := scanDirectives(tmpl, *sealed); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
+	defs := flag.Args()
+	if len(defs) == 0 {
 		usage()
 	}
-	if err := sumgen(def); err != nil {
+
+	if *check {
+		for _, def := range defs {
+			if err := checkExhaustive(def); err != nil {
+				log.Fatal(err)
+			}
+		}
+		return
+	}
+
+	if err := sumgen(defs, tmpl, *sealed); err != nil {
 		log.Fatal(err)
 	}
 }
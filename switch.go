@@ -0,0 +1,157 @@
+package main
+
+import (
+	"fmt"
+	"go/ast"
+	"go/types"
+	"os"
+	"sort"
+	"strings"
+
+	"golang.org/x/tools/go/packages"
+)
+
+// paramName derives a callback parameter identifier for rhs, e.g. IntLit ->
+// intLit, *BinOp -> binOp.
+func paramName(r rhs) string {
+	if r.Type == "" {
+		return "v"
+	}
+	return strings.ToLower(r.Type[:1]) + r.Type[1:]
+}
+
+// genSwitch generates a companion SwitchLhs function that dispatches on the
+// concrete type of a sum value, one callback per declared RHS variant. This
+// makes the interface behave like a closed sum type at call sites: adding a
+// new RHS forces every SwitchLhs call to pass a new callback or fail to
+// compile.
+func genSwitch(sum def) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "func Switch%s(v %s", sum.Lhs, sum.Lhs)
+	for _, r := range sum.Rhs {
+		ptr := ""
+		if r.Ptr {
+			ptr = "*"
+		}
+		fmt.Fprintf(&b, ", %s func(%s%s)", paramName(r), ptr, r.Type)
+	}
+	b.WriteString(") error {\n\tswitch x := v.(type) {\n")
+	for _, r := range sum.Rhs {
+		ptr := ""
+		if r.Ptr {
+			ptr = "*"
+		}
+		fmt.Fprintf(&b, "\tcase %s%s:\n\t\t%s(x)\n", ptr, r.Type, paramName(r))
+	}
+	fmt.Fprintf(&b, "\tdefault:\n\t\treturn fmt.Errorf(\"unhandled %%T for %s\", v)\n\t}\n\treturn nil\n}\n", sum.Lhs)
+	return b.String()
+}
+
+// checkExhaustive loads the package in the current directory and reports
+// every existing `switch v := x.(type)` over the interface named in def that
+// is missing an explicit case for one of def's declared RHS types. Unlike
+// genSwitch, it doesn't generate anything: it only flags switches a human
+// already wrote that have drifted out of sync with the sum's RHS list.
+func checkExhaustive(def string) error {
+	cfg := &packages.Config{
+		Mode: packages.NeedName |
+			packages.NeedFiles |
+			packages.NeedImports |
+			packages.NeedTypes |
+			packages.NeedTypesInfo |
+			packages.NeedSyntax,
+	}
+	pkgs, err := packages.Load(cfg)
+	if err != nil {
+		return err
+	}
+	if len(pkgs) != 1 {
+		return fmt.Errorf("could not find Go package in current directory")
+	}
+	pkg := pkgs[0]
+
+	sum, err := parseDef(def)
+	if err != nil {
+		return err
+	}
+	lhsObj := pkg.Types.Scope().Lookup(sum.Lhs)
+	if lhsObj == nil {
+		return fmt.Errorf("no interface type with name %q", sum.Lhs)
+	}
+	if _, ok := lhsObj.Type().Underlying().(*types.Interface); !ok {
+		return fmt.Errorf("no interface type with name %q", sum.Lhs)
+	}
+
+	required := make(map[string]bool, len(sum.Rhs))
+	for _, r := range sum.Rhs {
+		o := pkg.Types.Scope().Lookup(r.Type)
+		if o == nil {
+			return fmt.Errorf("no type with name %q", r.Type)
+		}
+		name := types.TypeString(o.Type(), types.RelativeTo(pkg.Types))
+		if r.Ptr {
+			name = "*" + name
+		}
+		required[name] = true
+	}
+
+	var found int
+	for _, file := range pkg.Syntax {
+		ast.Inspect(file, func(n ast.Node) bool {
+			sw, ok := n.(*ast.TypeSwitchStmt)
+			if !ok {
+				return true
+			}
+			var subject ast.Expr
+			switch a := sw.Assign.(type) {
+			case *ast.ExprStmt:
+				te, ok := a.X.(*ast.TypeAssertExpr)
+				if !ok {
+					return true
+				}
+				subject = te.X
+			case *ast.AssignStmt:
+				if len(a.Rhs) != 1 {
+					return true
+				}
+				te, ok := a.Rhs[0].(*ast.TypeAssertExpr)
+				if !ok {
+					return true
+				}
+				subject = te.X
+			default:
+				return true
+			}
+			if !types.Identical(pkg.TypesInfo.TypeOf(subject), lhsObj.Type()) {
+				return true
+			}
+
+			have := make(map[string]bool)
+			for _, stmt := range sw.Body.List {
+				cc := stmt.(*ast.CaseClause)
+				for _, expr := range cc.List {
+					if t := pkg.TypesInfo.TypeOf(expr); t != nil {
+						have[types.TypeString(t, types.RelativeTo(pkg.Types))] = true
+					}
+				}
+			}
+			var missing []string
+			for name := range required {
+				if !have[name] {
+					missing = append(missing, name)
+				}
+			}
+			if len(missing) > 0 {
+				sort.Strings(missing)
+				pos := pkg.Fset.Position(sw.Pos())
+				fmt.Fprintf(os.Stderr, "%s: switch over %s missing case(s) for %s\n", pos, sum.Lhs, strings.Join(missing, ", "))
+				found += len(missing)
+			}
+			return true
+		})
+	}
+	if found > 0 {
+		return fmt.Errorf("%d missing case(s) found", found)
+	}
+	return nil
+}
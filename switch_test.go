@@ -0,0 +1,74 @@
+package main
+
+import (
+	"go/parser"
+	"go/token"
+	"strings"
+	"testing"
+)
+
+func TestGenSwitch(t *testing.T) {
+	sum := def{
+		Lhs: "Expr",
+		Rhs: []rhs{
+			{Type: "IntLit"},
+			{Ptr: true, Type: "BinOp"},
+		},
+	}
+	src := genSwitch(sum)
+	for _, want := range []string{
+		"func SwitchExpr(v Expr, intLit func(IntLit), binOp func(*BinOp)) error {",
+		"case IntLit:",
+		"case *BinOp:",
+	} {
+		if !strings.Contains(src, want) {
+			t.Errorf("genSwitch output missing %q:\n%s", want, src)
+		}
+	}
+	if _, err := parser.ParseFile(token.NewFileSet(), "", "package p\n\nimport \"fmt\"\n\ntype Expr interface{}\ntype IntLit struct{}\ntype BinOp struct{}\n\n"+src, 0); err != nil {
+		t.Fatalf("genSwitch produced unparseable source: %v\n%s", err, src)
+	}
+}
+
+func TestCheckExhaustiveReportsMissingCase(t *testing.T) {
+	chdirTemp(t, `package p
+
+type Expr interface{ isExpr() }
+type IntLit struct{}
+type BinOp struct{}
+
+func (IntLit) isExpr() {}
+func (BinOp) isExpr()  {}
+
+func eval(e Expr) {
+	switch e.(type) {
+	case IntLit:
+	}
+}
+`)
+	if err := checkExhaustive("Expr = IntLit | BinOp"); err == nil {
+		t.Fatal("checkExhaustive returned nil, want an error for the switch missing a BinOp case")
+	}
+}
+
+func TestCheckExhaustiveAcceptsCompleteSwitch(t *testing.T) {
+	chdirTemp(t, `package p
+
+type Expr interface{ isExpr() }
+type IntLit struct{}
+type BinOp struct{}
+
+func (IntLit) isExpr() {}
+func (BinOp) isExpr()  {}
+
+func eval(e Expr) {
+	switch e.(type) {
+	case IntLit:
+	case BinOp:
+	}
+}
+`)
+	if err := checkExhaustive("Expr = IntLit | BinOp"); err != nil {
+		t.Fatalf("checkExhaustive returned an error for a complete switch: %v", err)
+	}
+}
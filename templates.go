@@ -0,0 +1,119 @@
+package main
+
+import (
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"strings"
+	"text/template"
+
+	"golang.org/x/tools/go/packages"
+)
+
+// stubData is the value a -body template executes against.
+type stubData struct {
+	Recv        string   // receiver type name, without the pointer star
+	Name        string   // method name
+	Sig         string   // formatted parameter/result signature, e.g. "(x int) string"
+	Results     []string // formatted result types, in order
+	ZeroResults string   // comma-separated zero-value expression for each result
+}
+
+// builtinBodies are the stub bodies sumgen ships out of the box. zero and
+// todo both rely on Results/ZeroResults being empty for void methods, so a
+// bare "return" is never emitted where one isn't needed.
+var builtinBodies = map[string]string{
+	"panic": `panic("default implementation")`,
+	"zero":  `{{if .Results}}return {{.ZeroResults}}{{end}}`,
+	"todo":  `sumgenTODO("{{.Recv}}.{{.Name}}"){{if .Results}}; return {{.ZeroResults}}{{end}}`,
+}
+
+// parseBodyTemplate resolves spec to a body template: spec may name one of
+// the builtins above, or be the text of a template itself.
+func parseBodyTemplate(spec string) (*template.Template, error) {
+	if src, ok := builtinBodies[spec]; ok {
+		spec = src
+	}
+	return template.New("body").Parse(spec)
+}
+
+// zeroResults formats the result types of sig and a comma-separated
+// expression that zero-initializes each one, so -body templates like "zero"
+// can produce a `return` statement without the user writing one per method.
+func zeroResults(sig *types.Signature, qualifier types.Qualifier) ([]string, string) {
+	res := sig.Results()
+	results := make([]string, res.Len())
+	zeros := make([]string, res.Len())
+	for i := 0; i < res.Len(); i++ {
+		t := res.At(i).Type()
+		results[i] = types.TypeString(t, qualifier)
+		zeros[i] = zeroExpr(t, qualifier)
+	}
+	return results, strings.Join(zeros, ", ")
+}
+
+// zeroExpr returns a Go expression for the zero value of t, using
+// types.Default to resolve untyped constants the same way the compiler
+// would assign them a type.
+func zeroExpr(t types.Type, qualifier types.Qualifier) string {
+	t = types.Default(t)
+	switch u := t.Underlying().(type) {
+	case *types.Basic:
+		switch {
+		case u.Info()&types.IsBoolean != 0:
+			return "false"
+		case u.Info()&types.IsString != 0:
+			return `""`
+		case u.Info()&types.IsNumeric != 0:
+			return "0"
+		default:
+			return "nil"
+		}
+	case *types.Pointer, *types.Interface, *types.Slice, *types.Map, *types.Chan, *types.Signature:
+		return "nil"
+	default:
+		return types.TypeString(t, qualifier) + "{}"
+	}
+}
+
+// methodOrder parses the file declaring lhsObj and returns the textual order
+// its interface methods appear in, so clean can emit stubs in the same order
+// as the interface instead of go/types' scope order, which is sorted
+// lexicographically and would reshuffle the generated file on every run for
+// no reason. It returns a nil order, not an error, if lhsObj isn't an
+// interface declared in source (e.g. it comes from a dependency).
+func methodOrder(pkg *packages.Package, lhsObj types.Object) ([]string, error) {
+	if lhsObj == nil {
+		return nil, nil
+	}
+	pos := pkg.Fset.Position(lhsObj.Pos())
+	if pos.Filename == "" {
+		return nil, nil
+	}
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, pos.Filename, nil, 0)
+	if err != nil {
+		return nil, fmt.Errorf("reading method order from %s: %v", pos.Filename, err)
+	}
+	var order []string
+	ast.Inspect(file, func(n ast.Node) bool {
+		ts, ok := n.(*ast.TypeSpec)
+		if !ok || ts.Name.Name != lhsObj.Name() {
+			return order == nil
+		}
+		it, ok := ts.Type.(*ast.InterfaceType)
+		if !ok {
+			return false
+		}
+		for _, m := range it.Methods.List {
+			if len(m.Names) == 0 {
+				continue // embedded interface, not a method
+			}
+			order = append(order, m.Names[0].Name)
+		}
+		return false
+	})
+	return order, nil
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"golang.org/x/tools/go/packages"
+)
+
+// loadTestPackage type-checks src as the sole file of a throwaway module in a
+// temp directory and returns the loaded package.
+func loadTestPackage(t *testing.T, src string) *packages.Package {
+	t.Helper()
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "go.mod"), []byte("module sumgentest\n\ngo 1.21\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "pkg.go"), []byte(src), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	cfg := &packages.Config{
+		Dir: dir,
+		Mode: packages.NeedName | packages.NeedFiles | packages.NeedImports |
+			packages.NeedTypes | packages.NeedTypesInfo | packages.NeedSyntax | packages.NeedDeps,
+	}
+	pkgs, err := packages.Load(cfg, ".")
+	if err != nil {
+		t.Fatalf("packages.Load: %v", err)
+	}
+	if len(pkgs) != 1 || len(pkgs[0].Errors) > 0 {
+		t.Fatalf("unexpected package load result: %+v", pkgs)
+	}
+	return pkgs[0]
+}
+
+func TestMethodOrder(t *testing.T) {
+	pkg := loadTestPackage(t, `package pkg
+
+type Node interface {
+	Pos() int
+	Name() string
+	End() int
+}
+`)
+	obj := pkg.Types.Scope().Lookup("Node")
+	if obj == nil {
+		t.Fatal("Node not found in package scope")
+	}
+	order, err := methodOrder(pkg, obj)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"Pos", "Name", "End"}
+	if len(order) != len(want) {
+		t.Fatalf("order = %v, want %v", order, want)
+	}
+	for i := range want {
+		if order[i] != want[i] {
+			t.Fatalf("order = %v, want %v", order, want)
+		}
+	}
+}
+
+func TestMethodOrderNilForNonInterface(t *testing.T) {
+	pkg := loadTestPackage(t, `package pkg
+
+type T struct{}
+`)
+	obj := pkg.Types.Scope().Lookup("T")
+	if obj == nil {
+		t.Fatal("T not found in package scope")
+	}
+	order, err := methodOrder(pkg, obj)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if order != nil {
+		t.Fatalf("order = %v, want nil for a non-interface type", order)
+	}
+}
+
+func TestParseBodyTemplateBuiltins(t *testing.T) {
+	for name := range builtinBodies {
+		tmpl, err := parseBodyTemplate(name)
+		if err != nil {
+			t.Fatalf("parseBodyTemplate(%q): %v", name, err)
+		}
+		var buf bytes.Buffer
+		data := stubData{Recv: "T", Name: "M", Sig: "()"}
+		if err := tmpl.Execute(&buf, data); err != nil {
+			t.Fatalf("executing %q template: %v", name, err)
+		}
+	}
+}